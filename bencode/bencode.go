@@ -28,6 +28,12 @@ type Decoder struct {
 	data    []byte
 	depth   int
 	RawInfo []byte
+
+	// Consumed is the number of bytes of data the last Decode/Unmarshal
+	// call parsed. Callers that embed a bencoded value inside a larger
+	// message (e.g. BEP 9 metadata pieces, which append raw bytes after
+	// the dict) use it to find where the value ends.
+	Consumed int
 }
 
 func NewDecoder(data []byte) *Decoder {
@@ -36,7 +42,8 @@ func NewDecoder(data []byte) *Decoder {
 
 // Decode is the entry point
 func (d *Decoder) Decode() (any, error) {
-	val, _, err := d.parseValue(d.data)
+	val, consumed, err := d.parseValue(d.data)
+	d.Consumed = consumed
 	return val, err
 }
 
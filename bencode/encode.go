@@ -0,0 +1,214 @@
+package bencode
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Marshaler lets a type control its own bencode representation, in the
+// same spirit as encoding/json.Marshaler.
+type Marshaler interface {
+	MarshalBencode() ([]byte, error)
+}
+
+// Encoder writes the bencode representation of Go values to an internal
+// buffer, the mirror image of Decoder.
+type Encoder struct {
+	buf bytes.Buffer
+}
+
+func NewEncoder() *Encoder {
+	return &Encoder{}
+}
+
+// Encode appends the bencode encoding of v to the encoder's buffer.
+func (e *Encoder) Encode(v any) error {
+	return e.encodeValue(reflect.ValueOf(v))
+}
+
+// Bytes returns everything written so far.
+func (e *Encoder) Bytes() []byte {
+	return e.buf.Bytes()
+}
+
+// Marshal returns the bencode encoding of v.
+func Marshal(v any) ([]byte, error) {
+	e := NewEncoder()
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	return e.Bytes(), nil
+}
+
+func (e *Encoder) encodeValue(v reflect.Value) error {
+	if !v.IsValid() {
+		return fmt.Errorf("bencode: cannot encode invalid value")
+	}
+
+	if v.CanInterface() {
+		if m, ok := v.Interface().(Marshaler); ok {
+			b, err := m.MarshalBencode()
+			if err != nil {
+				return err
+			}
+			e.buf.Write(b)
+			return nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return fmt.Errorf("bencode: cannot encode nil pointer")
+		}
+		return e.encodeValue(v.Elem())
+	case reflect.Interface:
+		return e.encodeValue(v.Elem())
+	case reflect.String:
+		e.encodeString([]byte(v.String()))
+		return nil
+	case reflect.Slice, reflect.Array:
+		return e.encodeSliceOrArray(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(v.Int())
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		e.encodeInt(int64(v.Uint()))
+		return nil
+	case reflect.Bool:
+		if v.Bool() {
+			e.encodeInt(1)
+		} else {
+			e.encodeInt(0)
+		}
+		return nil
+	case reflect.Map:
+		return e.encodeMap(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	default:
+		return fmt.Errorf("bencode: unsupported type %s", v.Type())
+	}
+}
+
+func (e *Encoder) encodeInt(n int64) {
+	e.buf.WriteByte('i')
+	e.buf.WriteString(strconv.FormatInt(n, 10))
+	e.buf.WriteByte('e')
+}
+
+func (e *Encoder) encodeString(b []byte) {
+	e.buf.WriteString(strconv.Itoa(len(b)))
+	e.buf.WriteByte(':')
+	e.buf.Write(b)
+}
+
+func (e *Encoder) encodeSliceOrArray(v reflect.Value) error {
+	// []byte (and [N]byte) are bencode strings, not lists.
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		if v.Kind() == reflect.Array {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			e.encodeString(b)
+		} else {
+			e.encodeString(v.Bytes())
+		}
+		return nil
+	}
+
+	e.buf.WriteByte('l')
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encodeValue(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	e.buf.WriteByte('e')
+	return nil
+}
+
+func (e *Encoder) encodeMap(v reflect.Value) error {
+	if v.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("bencode: map key type %s not supported, only string keys", v.Type().Key())
+	}
+
+	keys := v.MapKeys()
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.String()
+	}
+	sort.Strings(names)
+
+	e.buf.WriteByte('d')
+	for _, name := range names {
+		e.encodeString([]byte(name))
+		if err := e.encodeValue(v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))); err != nil {
+			return err
+		}
+	}
+	e.buf.WriteByte('e')
+	return nil
+}
+
+type taggedField struct {
+	name      string
+	omitempty bool
+	value     reflect.Value
+}
+
+func (e *Encoder) encodeStruct(v reflect.Value) error {
+	t := v.Type()
+	fields := make([]taggedField, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue // unexported
+		}
+
+		tag, ok := sf.Tag.Lookup("bencode")
+		if !ok {
+			continue
+		}
+		name, omitempty := parseTag(tag)
+		if name == "-" {
+			continue
+		}
+
+		fields = append(fields, taggedField{name: name, omitempty: omitempty, value: v.Field(i)})
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+
+	e.buf.WriteByte('d')
+	for _, f := range fields {
+		if f.omitempty && isEmptyValue(f.value) {
+			continue
+		}
+		e.encodeString([]byte(f.name))
+		if err := e.encodeValue(f.value); err != nil {
+			return err
+		}
+	}
+	e.buf.WriteByte('e')
+	return nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
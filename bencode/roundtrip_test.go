@@ -0,0 +1,118 @@
+package bencode
+
+import "testing"
+
+type roundtripInfo struct {
+	PieceLength int64           `bencode:"piece length"`
+	Pieces      string          `bencode:"pieces"`
+	Name        string          `bencode:"name"`
+	Length      int64           `bencode:"length,omitempty"`
+	Files       []roundtripFile `bencode:"files,omitempty"`
+}
+
+type roundtripFile struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+}
+
+type roundtripTorrent struct {
+	Announce     string        `bencode:"announce"`
+	AnnounceList [][]string    `bencode:"announce-list,omitempty"`
+	Info         roundtripInfo `bencode:"info"`
+}
+
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	in := roundtripTorrent{
+		Announce:     "udp://tracker.example:80/announce",
+		AnnounceList: [][]string{{"udp://tracker.example:80/announce"}, {"http://backup.example/announce"}},
+		Info: roundtripInfo{
+			PieceLength: 16384,
+			Pieces:      "01234567890123456789",
+			Name:        "multi-file",
+			Files: []roundtripFile{
+				{Length: 100, Path: []string{"a.txt"}},
+				{Length: 200, Path: []string{"subdir", "b.txt"}},
+			},
+		},
+	}
+
+	data, err := Marshal(&in)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var out roundtripTorrent
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if out.Announce != in.Announce {
+		t.Errorf("Announce = %q, want %q", out.Announce, in.Announce)
+	}
+	if len(out.AnnounceList) != len(in.AnnounceList) {
+		t.Fatalf("AnnounceList = %v, want %v", out.AnnounceList, in.AnnounceList)
+	}
+	for i := range in.AnnounceList {
+		if len(out.AnnounceList[i]) != 1 || out.AnnounceList[i][0] != in.AnnounceList[i][0] {
+			t.Errorf("AnnounceList[%d] = %v, want %v", i, out.AnnounceList[i], in.AnnounceList[i])
+		}
+	}
+	if out.Info.PieceLength != in.Info.PieceLength {
+		t.Errorf("Info.PieceLength = %d, want %d", out.Info.PieceLength, in.Info.PieceLength)
+	}
+	if out.Info.Pieces != in.Info.Pieces {
+		t.Errorf("Info.Pieces = %q, want %q", out.Info.Pieces, in.Info.Pieces)
+	}
+	if out.Info.Name != in.Info.Name {
+		t.Errorf("Info.Name = %q, want %q", out.Info.Name, in.Info.Name)
+	}
+	if len(out.Info.Files) != len(in.Info.Files) {
+		t.Fatalf("Info.Files = %v, want %v", out.Info.Files, in.Info.Files)
+	}
+	for i := range in.Info.Files {
+		wantFile := in.Info.Files[i]
+		gotFile := out.Info.Files[i]
+		if gotFile.Length != wantFile.Length {
+			t.Errorf("Files[%d].Length = %d, want %d", i, gotFile.Length, wantFile.Length)
+		}
+		if len(gotFile.Path) != len(wantFile.Path) {
+			t.Fatalf("Files[%d].Path = %v, want %v", i, gotFile.Path, wantFile.Path)
+		}
+		for j := range wantFile.Path {
+			if gotFile.Path[j] != wantFile.Path[j] {
+				t.Errorf("Files[%d].Path[%d] = %q, want %q", i, j, gotFile.Path[j], wantFile.Path[j])
+			}
+		}
+	}
+}
+
+func TestMarshalOmitsEmptyOptionalFields(t *testing.T) {
+	in := roundtripTorrent{
+		Announce: "udp://tracker.example:80/announce",
+		Info: roundtripInfo{
+			PieceLength: 16384,
+			Pieces:      "01234567890123456789",
+			Name:        "single-file",
+		},
+	}
+
+	data, err := Marshal(&in)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var out roundtripTorrent
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if out.AnnounceList != nil {
+		t.Errorf("AnnounceList = %v, want nil", out.AnnounceList)
+	}
+	if out.Info.Length != 0 {
+		t.Errorf("Info.Length = %d, want 0", out.Info.Length)
+	}
+	if out.Info.Files != nil {
+		t.Errorf("Info.Files = %v, want nil", out.Info.Files)
+	}
+}
@@ -0,0 +1,16 @@
+package bencode
+
+import "strings"
+
+// parseTag splits a struct tag of the form `bencode:"name,omitempty"` into
+// the dict key and whether empty values should be skipped when encoding.
+func parseTag(tag string) (name string, omitempty bool) {
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty
+}
@@ -0,0 +1,164 @@
+package bencode
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Unmarshal decodes data and stores the result in v, which must be a
+// non-nil pointer. Struct fields are matched by their `bencode` tag the
+// same way Marshal writes them.
+func Unmarshal(data []byte, v any) error {
+	return NewDecoder(data).Unmarshal(v)
+}
+
+// Unmarshal decodes the decoder's data directly into v, instead of
+// returning the untyped any tree that Decode does. RawInfo is still
+// populated as usual, so callers that need the raw info dict (e.g. to
+// hash it) can read it off the decoder afterwards.
+func (d *Decoder) Unmarshal(v any) error {
+	val, err := d.Decode()
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("bencode: Unmarshal requires a non-nil pointer")
+	}
+
+	return fillValue(val, rv.Elem())
+}
+
+func fillValue(val any, rv reflect.Value) error {
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return fillValue(val, rv.Elem())
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		m, ok := val.(map[string]any)
+		if !ok {
+			return fmt.Errorf("bencode: cannot unmarshal into struct %s, source is not a dict", rv.Type())
+		}
+		return fillStruct(m, rv)
+	case reflect.String:
+		b, ok := val.([]byte)
+		if !ok {
+			return fmt.Errorf("bencode: cannot unmarshal into string, source is not a byte string")
+		}
+		rv.SetString(string(b))
+	case reflect.Slice:
+		return fillSlice(val, rv)
+	case reflect.Map:
+		return fillMap(val, rv)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := val.(int64)
+		if !ok {
+			return fmt.Errorf("bencode: cannot unmarshal into %s, source is not an integer", rv.Type())
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := val.(int64)
+		if !ok {
+			return fmt.Errorf("bencode: cannot unmarshal into %s, source is not an integer", rv.Type())
+		}
+		rv.SetUint(uint64(n))
+	case reflect.Bool:
+		n, ok := val.(int64)
+		if !ok {
+			return fmt.Errorf("bencode: cannot unmarshal into bool, source is not an integer")
+		}
+		rv.SetBool(n != 0)
+	case reflect.Interface:
+		if rv.NumMethod() == 0 {
+			rv.Set(reflect.ValueOf(val))
+			return nil
+		}
+		return fmt.Errorf("bencode: cannot unmarshal into interface %s", rv.Type())
+	default:
+		return fmt.Errorf("bencode: unsupported unmarshal target %s", rv.Type())
+	}
+
+	return nil
+}
+
+func fillStruct(m map[string]any, rv reflect.Value) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue // unexported
+		}
+
+		tag, ok := sf.Tag.Lookup("bencode")
+		if !ok {
+			continue
+		}
+		name, _ := parseTag(tag)
+		if name == "-" {
+			continue
+		}
+
+		fieldVal, ok := m[name]
+		if !ok {
+			continue
+		}
+
+		if err := fillValue(fieldVal, rv.Field(i)); err != nil {
+			return fmt.Errorf("bencode: field %s: %w", sf.Name, err)
+		}
+	}
+	return nil
+}
+
+func fillSlice(val any, rv reflect.Value) error {
+	// []byte is a bencode string, not a list.
+	if rv.Type().Elem().Kind() == reflect.Uint8 {
+		b, ok := val.([]byte)
+		if !ok {
+			return fmt.Errorf("bencode: cannot unmarshal into []byte, source is not a byte string")
+		}
+		rv.SetBytes(b)
+		return nil
+	}
+
+	list, ok := val.([]any)
+	if !ok {
+		return fmt.Errorf("bencode: cannot unmarshal into %s, source is not a list", rv.Type())
+	}
+
+	out := reflect.MakeSlice(rv.Type(), len(list), len(list))
+	for i, item := range list {
+		if err := fillValue(item, out.Index(i)); err != nil {
+			return err
+		}
+	}
+	rv.Set(out)
+	return nil
+}
+
+func fillMap(val any, rv reflect.Value) error {
+	if rv.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("bencode: map key type %s not supported, only string keys", rv.Type().Key())
+	}
+
+	m, ok := val.(map[string]any)
+	if !ok {
+		return fmt.Errorf("bencode: cannot unmarshal into %s, source is not a dict", rv.Type())
+	}
+
+	out := reflect.MakeMapWithSize(rv.Type(), len(m))
+	for k, v := range m {
+		elem := reflect.New(rv.Type().Elem()).Elem()
+		if err := fillValue(v, elem); err != nil {
+			return err
+		}
+		out.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), elem)
+	}
+	rv.Set(out)
+	return nil
+}
@@ -18,8 +18,7 @@ func main() {
 	copy(peerID[:], peerIDString)
 
 	fmt.Println("Contacting tracker...")
-	url, _ := t.BuildTrackerURL(infoHash, peerIDString, 6881)
-	peers, err := torrent.RequestPeers(url)
+	peers, err := t.RequestPeers(infoHash, peerID, 6881)
 	if err != nil {
 		log.Fatalf("Tracker error: %v", err)
 	}
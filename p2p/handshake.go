@@ -5,10 +5,18 @@ import (
 	"io"
 )
 
+// extensionProtocolBit is reserved byte 5, bit 0x10 ("LTEP"), the flag
+// peers use to advertise BEP 10 extended message support.
+const extensionProtocolBit = 0x10
+
 type Handshake struct {
 	Pstr     string
 	InfoHash [20]byte
 	PeerID   [20]byte
+
+	// Extensions advertises (on Serialize) or records (from Unserialize)
+	// support for the BEP 10 extension protocol.
+	Extensions bool
 }
 
 func NewHandshake(infoHash [20]byte, peerID [20]byte) *Handshake {
@@ -24,7 +32,11 @@ func (h *Handshake) Serialize() []byte {
 	buf[0] = byte(len(h.Pstr)) // always is 19
 	curr := 1
 	curr += copy(buf[curr:], []byte(h.Pstr))
-	curr += copy(buf[curr:], make([]byte, 8)) // reserved 8 bytes
+	reserved := make([]byte, 8)
+	if h.Extensions {
+		reserved[5] |= extensionProtocolBit
+	}
+	curr += copy(buf[curr:], reserved)
 	curr += copy(buf[curr:], h.InfoHash[:])
 	curr += copy(buf[curr:], h.PeerID[:])
 	return buf
@@ -41,9 +53,16 @@ func Unserialize(r io.Reader) (*Handshake, error) {
 	if pstrlen == 0 {
 		return nil, fmt.Errorf("pstrlen cannot be 0")
 	}
+	// pstrlen+1+8+20+20 must fit in the fixed 68-byte handshake; a
+	// peer-supplied pstrlen outside that range would otherwise slice buf
+	// out of bounds and panic.
+	if pstrlen+1+8+20+20 > len(buf) {
+		return nil, fmt.Errorf("pstrlen %d too large for handshake", pstrlen)
+	}
 
 	res := &Handshake{
-		Pstr: string(buf[1 : pstrlen+1]),
+		Pstr:       string(buf[1 : pstrlen+1]),
+		Extensions: buf[pstrlen+1+5]&extensionProtocolBit != 0,
 	}
 	copy(res.InfoHash[:], buf[pstrlen+1+8:pstrlen+1+8+20])
 	copy(res.PeerID[:], buf[pstrlen+1+8+20:])
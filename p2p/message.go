@@ -2,6 +2,7 @@ package p2p
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
 )
 
@@ -16,8 +17,16 @@ const (
 	MsgRequest       uint8 = 6
 	MsgPiece         uint8 = 7
 	MsgCancel        uint8 = 8
+	MsgExtended      uint8 = 20 // BEP 10
 )
 
+// maxMessageLength caps the length prefix Read will allocate for. The
+// largest legitimate message is a MsgPiece carrying one block (index +
+// begin + up to a few times the usual 16 KiB request size); anything
+// beyond that is either a buggy peer or an attacker trying to force a
+// multi-gigabyte allocation from the 4-byte length prefix alone.
+const maxMessageLength = 256 * 1024
+
 // Message represents a BitTorrent message
 type Message struct {
 	ID      uint8
@@ -55,6 +64,9 @@ func Read(r io.Reader) (*Message, error) {
 	if length == 0 {
 		return nil, nil
 	}
+	if length > maxMessageLength {
+		return nil, fmt.Errorf("message length %d exceeds max %d", length, maxMessageLength)
+	}
 
 	messageBuf := make([]byte, length)
 	_, err = io.ReadFull(r, messageBuf)
@@ -0,0 +1,27 @@
+// Package storage provides pluggable backends for where torrent piece
+// data actually lives: plain files laid out across a multi-file
+// torrent, a memory-mapped single file, or plain memory for tests.
+package storage
+
+// Backend is where a Torrent's piece data is read from and written to.
+// Offsets passed to PieceReadAt/PieceWriteAt are relative to the start
+// of the piece at index, not the start of the torrent.
+type Backend interface {
+	PieceReadAt(index int, off int64, p []byte) (int, error)
+	PieceWriteAt(index int, off int64, p []byte) (int, error)
+
+	// MarkComplete records that a piece has been fully downloaded and
+	// its hash verified. Backends that don't need to track this (e.g.
+	// plain files, where a successful write is already durable) may
+	// treat it as a no-op.
+	MarkComplete(index int) error
+
+	Close() error
+}
+
+// FileInfo describes one file in a torrent's (possibly multi-file)
+// layout, in the order it appears in the info dict.
+type FileInfo struct {
+	Path   string
+	Length int64
+}
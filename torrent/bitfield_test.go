@@ -0,0 +1,91 @@
+package torrent
+
+import "testing"
+
+func TestBitfieldHasPiece(t *testing.T) {
+	bf := Bitfield([]byte{0b10000000, 0b01000000})
+
+	tests := []struct {
+		index int
+		want  bool
+	}{
+		{0, true},
+		{1, false},
+		{7, false},
+		{8, false},
+		{9, true},
+		{15, false},
+	}
+
+	for _, tt := range tests {
+		if got := bf.HasPiece(tt.index); got != tt.want {
+			t.Errorf("HasPiece(%d) = %v, want %v", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestBitfieldHasPieceOutOfRange(t *testing.T) {
+	bf := Bitfield([]byte{0xFF})
+
+	if bf.HasPiece(-1) {
+		t.Error("HasPiece(-1) = true, want false")
+	}
+	if bf.HasPiece(8) {
+		t.Error("HasPiece(8) = true, want false")
+	}
+}
+
+func TestBitfieldSetPiece(t *testing.T) {
+	bf := make(Bitfield, 2)
+
+	bf.SetPiece(0)
+	bf.SetPiece(9)
+	bf.SetPiece(15)
+
+	want := Bitfield{0b10000000, 0b01000001}
+	for i := range want {
+		if bf[i] != want[i] {
+			t.Errorf("byte %d = %08b, want %08b", i, bf[i], want[i])
+		}
+	}
+
+	for i := 0; i < 16; i++ {
+		want := i == 0 || i == 9 || i == 15
+		if got := bf.HasPiece(i); got != want {
+			t.Errorf("HasPiece(%d) after SetPiece = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestBitfieldSetPieceOutOfRange(t *testing.T) {
+	bf := make(Bitfield, 1)
+
+	// Must not panic and must not touch existing bits.
+	bf.SetPiece(-1)
+	bf.SetPiece(8)
+	bf.SetPiece(100)
+
+	if bf[0] != 0 {
+		t.Errorf("out-of-range SetPiece modified byte 0: %08b", bf[0])
+	}
+}
+
+func TestBitfieldEverySingleBit(t *testing.T) {
+	const numPieces = 64
+	bf := make(Bitfield, (numPieces+7)/8)
+
+	for i := 0; i < numPieces; i++ {
+		if bf.HasPiece(i) {
+			t.Fatalf("HasPiece(%d) = true before SetPiece", i)
+		}
+		bf.SetPiece(i)
+		if !bf.HasPiece(i) {
+			t.Fatalf("HasPiece(%d) = false after SetPiece", i)
+		}
+		for j := i + 1; j < numPieces; j++ {
+			if bf.HasPiece(j) {
+				t.Fatalf("HasPiece(%d) = true before its own SetPiece (while setting %d)", j, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package torrent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMagnetURIHexInfoHash(t *testing.T) {
+	uri := "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=My+File&tr=udp://a.example:80&tr=udp://b.example:80"
+
+	m, err := ParseMagnetURI(uri)
+	if err != nil {
+		t.Fatalf("ParseMagnetURI returned error: %v", err)
+	}
+
+	want := [20]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67}
+	if m.InfoHash != want {
+		t.Errorf("InfoHash = %x, want %x", m.InfoHash, want)
+	}
+	if m.DisplayName != "My File" {
+		t.Errorf("DisplayName = %q, want %q", m.DisplayName, "My File")
+	}
+	wantTrackers := []string{"udp://a.example:80", "udp://b.example:80"}
+	if !reflect.DeepEqual(m.Trackers, wantTrackers) {
+		t.Errorf("Trackers = %v, want %v", m.Trackers, wantTrackers)
+	}
+}
+
+func TestParseMagnetURIBase32InfoHash(t *testing.T) {
+	hex := [20]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67}
+	base32 := "AERUKZ4JVPG66AJDIVTYTK6N54ASGRLH"
+
+	m, err := ParseMagnetURI("magnet:?xt=urn:btih:" + base32)
+	if err != nil {
+		t.Fatalf("ParseMagnetURI returned error: %v", err)
+	}
+	if m.InfoHash != hex {
+		t.Errorf("InfoHash = %x, want %x", m.InfoHash, hex)
+	}
+}
+
+func TestParseMagnetURIRejectsNonMagnetScheme(t *testing.T) {
+	if _, err := ParseMagnetURI("http://example.com"); err == nil {
+		t.Error("expected error for non-magnet scheme, got nil")
+	}
+}
+
+func TestParseMagnetURIRejectsMissingXt(t *testing.T) {
+	if _, err := ParseMagnetURI("magnet:?dn=no-hash-here"); err == nil {
+		t.Error("expected error for missing xt parameter, got nil")
+	}
+}
+
+func TestDecodeInfoHashRejectsBadLength(t *testing.T) {
+	if _, err := decodeInfoHash("deadbeef"); err == nil {
+		t.Error("expected error for infohash with unexpected length, got nil")
+	}
+}
+
+func TestDecodeInfoHashRejectsInvalidHex(t *testing.T) {
+	notHex := "zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz"[:40]
+	if _, err := decodeInfoHash(notHex); err == nil {
+		t.Error("expected error for non-hex infohash, got nil")
+	}
+}
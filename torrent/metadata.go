@@ -0,0 +1,186 @@
+package torrent
+
+import (
+	"bittorrent/bencode"
+	"bittorrent/p2p"
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// localUtMetadataID is the extended message ID we advertise for
+// "ut_metadata" in our own handshake's m dict (BEP 10). Peers echo
+// metadata pieces back to us using this ID.
+const localUtMetadataID = 1
+
+const metadataBlockSize = 16384
+
+// fetchMetadata tries each peer in turn until one serves the full,
+// hash-verified info dict.
+func fetchMetadata(peers []Peer, infoHash [20]byte, peerID [20]byte) ([]byte, error) {
+	var lastErr error
+	for _, p := range peers {
+		data, err := fetchMetadataFromPeer(p, infoHash, peerID)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("no peer served metadata, last error: %w", lastErr)
+}
+
+func fetchMetadataFromPeer(p Peer, infoHash [20]byte, peerID [20]byte) ([]byte, error) {
+	address := net.JoinHostPort(p.IP.String(), strconv.Itoa(int(p.Port)))
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	hs := p2p.Handshake{Pstr: "BitTorrent protocol", InfoHash: infoHash, PeerID: peerID, Extensions: true}
+	if _, err := conn.Write(hs.Serialize()); err != nil {
+		return nil, err
+	}
+	res, err := p2p.Unserialize(conn)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(res.InfoHash[:], infoHash[:]) {
+		return nil, fmt.Errorf("%s: infohash mismatch", address)
+	}
+	if !res.Extensions {
+		return nil, fmt.Errorf("%s: peer does not support the extension protocol", address)
+	}
+
+	peerUtMetadataID, metadataSize, err := sendExtendedHandshake(conn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", address, err)
+	}
+
+	numPieces := (metadataSize + metadataBlockSize - 1) / metadataBlockSize
+	buf := make([]byte, metadataSize)
+
+	for i := int64(0); i < numPieces; i++ {
+		piece, err := requestMetadataPiece(conn, peerUtMetadataID, int(i))
+		if err != nil {
+			return nil, fmt.Errorf("%s: piece %d: %w", address, i, err)
+		}
+		copy(buf[i*metadataBlockSize:], piece)
+	}
+
+	hash := sha1.Sum(buf)
+	if !bytes.Equal(hash[:], infoHash[:]) {
+		return nil, fmt.Errorf("%s: metadata hash mismatch", address)
+	}
+
+	return buf, nil
+}
+
+// sendExtendedHandshake performs the BEP 10 extended handshake (extended
+// message ID 20, sub-ID 0) and returns the peer's ut_metadata ID and the
+// total metadata size it advertises.
+func sendExtendedHandshake(conn net.Conn) (peerUtMetadataID int64, metadataSize int64, err error) {
+	payload, err := bencode.Marshal(map[string]any{
+		"m": map[string]any{"ut_metadata": int64(localUtMetadataID)},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	msg := &p2p.Message{ID: p2p.MsgExtended, Payload: append([]byte{0}, payload...)}
+	if _, err := conn.Write(msg.Serialize()); err != nil {
+		return 0, 0, err
+	}
+
+	for {
+		reply, err := p2p.Read(conn)
+		if err != nil {
+			return 0, 0, err
+		}
+		if reply == nil || reply.ID != p2p.MsgExtended || len(reply.Payload) == 0 || reply.Payload[0] != 0 {
+			continue // not the extended handshake reply, keep waiting
+		}
+
+		decoder := bencode.NewDecoder(reply.Payload[1:])
+		val, err := decoder.Decode()
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid extended handshake: %w", err)
+		}
+		dict, ok := val.(map[string]any)
+		if !ok {
+			return 0, 0, fmt.Errorf("extended handshake is not a dict")
+		}
+
+		m, ok := dict["m"].(map[string]any)
+		if !ok {
+			return 0, 0, fmt.Errorf("peer does not advertise any extensions")
+		}
+		id, ok := m["ut_metadata"].(int64)
+		if !ok {
+			return 0, 0, fmt.Errorf("peer does not support ut_metadata")
+		}
+		size, ok := dict["metadata_size"].(int64)
+		if !ok {
+			return 0, 0, fmt.Errorf("peer did not advertise metadata_size")
+		}
+		if size <= 0 || size > bencode.MaxStringLength {
+			return 0, 0, fmt.Errorf("peer advertised implausible metadata_size %d", size)
+		}
+
+		return id, size, nil
+	}
+}
+
+// requestMetadataPiece requests one 16 KiB chunk of the info dict and
+// returns its raw bytes, stripping the bencoded dict the peer's reply
+// carries in front of them.
+func requestMetadataPiece(conn net.Conn, peerUtMetadataID int64, index int) ([]byte, error) {
+	payload, err := bencode.Marshal(map[string]any{
+		"msg_type": int64(0),
+		"piece":    int64(index),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &p2p.Message{ID: p2p.MsgExtended, Payload: append([]byte{byte(peerUtMetadataID)}, payload...)}
+	if _, err := conn.Write(msg.Serialize()); err != nil {
+		return nil, err
+	}
+
+	for {
+		reply, err := p2p.Read(conn)
+		if err != nil {
+			return nil, err
+		}
+		if reply == nil || reply.ID != p2p.MsgExtended || len(reply.Payload) == 0 || reply.Payload[0] != localUtMetadataID {
+			continue
+		}
+
+		body := reply.Payload[1:]
+		decoder := bencode.NewDecoder(body)
+		val, err := decoder.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata piece message: %w", err)
+		}
+		dict, ok := val.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("metadata piece message is not a dict")
+		}
+
+		msgType, _ := dict["msg_type"].(int64)
+		switch msgType {
+		case 1: // data
+			return body[decoder.Consumed:], nil
+		case 2: // reject
+			return nil, fmt.Errorf("peer rejected metadata piece %d", index)
+		default:
+			continue
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package torrent
+
+import (
+	"fmt"
+	"io"
+)
+
+// defaultReadahead is how many pieces a Reader keeps at
+// PriorityReadahead ahead of the read cursor; every Read applies it
+// through SetReadahead.
+const defaultReadahead = 4
+
+// SetReadahead raises the piece containing off to PriorityNow, the next
+// piece to PriorityNext, and the following pieces covering length bytes
+// to PriorityReadahead, so the scheduler fetches them ahead of
+// background pieces. It is safe to call before or during Download.
+func (t *Torrent) SetReadahead(off, length int64) {
+	if t.scheduler == nil || t.Info.PieceLength == 0 {
+		return
+	}
+
+	pieceLength := t.Info.PieceLength
+	nowIndex := int(off / pieceLength)
+	nextIndex := nowIndex + 1
+	readaheadPieces := int((length + pieceLength - 1) / pieceLength)
+
+	t.scheduler.SetPriority(nowIndex, PriorityNow)
+	t.scheduler.SetPriority(nextIndex, PriorityNext)
+	for i := 0; i < readaheadPieces; i++ {
+		t.scheduler.SetPriority(nextIndex+1+i, PriorityReadahead)
+	}
+}
+
+// waitForPiece blocks until piece index has been downloaded and
+// verified.
+func (t *Torrent) waitForPiece(index int) {
+	t.completedCond.L.Lock()
+	defer t.completedCond.L.Unlock()
+	for !t.completed.HasPiece(index) {
+		t.completedCond.Wait()
+	}
+}
+
+// Reader streams a Torrent's content, translating Read/Seek calls into
+// readahead-priority updates and blocking until the pieces it touches
+// have been downloaded, so playback can start before the whole torrent
+// is complete.
+type Reader struct {
+	t         *Torrent
+	pos       int64
+	readahead int64
+}
+
+// NewReader returns a Reader over t's content. Download must be running
+// (or already complete) for reads to make progress.
+func (t *Torrent) NewReader() io.ReadSeeker {
+	return &Reader{t: t, readahead: t.Info.PieceLength * defaultReadahead}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	t := r.t
+	total := t.totalLength()
+	if r.pos >= total {
+		return 0, io.EOF
+	}
+
+	t.SetReadahead(r.pos, r.readahead)
+
+	pieceIndex := int(r.pos / t.Info.PieceLength)
+	pieceOffset := r.pos % t.Info.PieceLength
+	t.waitForPiece(pieceIndex)
+
+	// Cap this read at the end of the piece we just waited for; the next
+	// Read call will wait for (and by then likely already have) the one
+	// after it.
+	pieceEnd := int64(pieceIndex+1) * t.Info.PieceLength
+	if pieceEnd > total {
+		pieceEnd = total
+	}
+	if max := pieceEnd - r.pos; int64(len(p)) > max {
+		p = p[:max]
+	}
+
+	n, err := t.Storage.PieceReadAt(pieceIndex, pieceOffset, p)
+	r.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	var newPos int64
+	switch whence {
+	case io.SeekStart:
+		newPos = offset
+	case io.SeekCurrent:
+		newPos = r.pos + offset
+	case io.SeekEnd:
+		newPos = r.t.totalLength() + offset
+	default:
+		return 0, fmt.Errorf("torrent: invalid whence %d", whence)
+	}
+	if newPos < 0 {
+		return 0, fmt.Errorf("torrent: negative seek position")
+	}
+
+	r.pos = newPos
+	return r.pos, nil
+}
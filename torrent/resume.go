@@ -0,0 +1,82 @@
+package torrent
+
+import (
+	"bittorrent/bencode"
+	"bittorrent/storage"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// resumeState is the small bencoded sidecar Download persists after
+// each verified piece, so an interrupted download can report progress
+// without re-fetching pieces already on disk.
+type resumeState struct {
+	Bitfield   []byte `bencode:"bitfield"`
+	Downloaded int64  `bencode:"downloaded"`
+	Uploaded   int64  `bencode:"uploaded"`
+}
+
+// resumeFilePath is the sidecar path for this torrent's output, e.g.
+// "ubuntu.iso.resume".
+func (t *Torrent) resumeFilePath() string {
+	return t.Info.Name + ".resume"
+}
+
+// persistResume writes the current completion bitfield and transfer
+// counters to the resume sidecar.
+func (t *Torrent) persistResume(downloaded, uploaded int64) error {
+	data, err := bencode.Marshal(&resumeState{
+		Bitfield:   []byte(t.completed),
+		Downloaded: downloaded,
+		Uploaded:   uploaded,
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(t.resumeFilePath(), data, 0666)
+}
+
+// loadResume reads back the resume sidecar written by persistResume. It
+// returns a nil state (and no error) if the sidecar doesn't exist, so
+// callers can fall back to a full VerifyLocalData rehash.
+func (t *Torrent) loadResume() (*resumeState, error) {
+	data, err := os.ReadFile(t.resumeFilePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &resumeState{}
+	if err := bencode.NewDecoder(data).Unmarshal(state); err != nil {
+		return nil, fmt.Errorf("invalid resume sidecar: %w", err)
+	}
+	return state, nil
+}
+
+// VerifyLocalData hashes every piece already present in backend against
+// the torrent's expected piece hashes, returning a Bitfield marking the
+// ones that are complete. Pieces that are missing, short, or
+// hash-mismatched are left unmarked so Download re-fetches them.
+func (t *Torrent) VerifyLocalData(backend storage.Backend) (Bitfield, error) {
+	pieces := t.CreatePieceList()
+	bitfield := make(Bitfield, (len(pieces)+7)/8)
+
+	buf := make([]byte, t.Info.PieceLength)
+	for _, p := range pieces {
+		pieceBuf := buf[:p.Length]
+		n, err := backend.PieceReadAt(p.Index, 0, pieceBuf)
+		if err != nil || n < p.Length {
+			continue
+		}
+
+		if sha1.Sum(pieceBuf) == p.Hash {
+			bitfield.SetPiece(p.Index)
+		}
+	}
+
+	return bitfield, nil
+}
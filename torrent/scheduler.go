@@ -0,0 +1,165 @@
+package torrent
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// PiecePriority controls how eagerly the scheduler serves a piece. A
+// Reader raises the piece under its cursor to PriorityNow, the piece
+// right after it to PriorityNext, and a readahead window to
+// PriorityReadahead so streaming playback doesn't have to wait for the
+// whole torrent, while everything else downloads in background order.
+type PiecePriority int
+
+const (
+	PriorityNone PiecePriority = iota
+	PriorityNormal
+	PriorityReadahead
+	PriorityNext
+	PriorityNow
+)
+
+// pieceJob is one entry in the scheduler's queue.
+type pieceJob struct {
+	work      *PieceWork
+	priority  PiecePriority
+	heapIndex int
+}
+
+// Scheduler is a priority queue of pending piece downloads, keyed by
+// piece index so SetPriority can re-prioritize a piece already queued
+// instead of enqueueing a duplicate.
+type Scheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	byIndex map[int]*pieceJob
+	queue   pieceHeap
+	closed  bool
+
+	// lastPriority remembers the most recent priority assigned to each
+	// piece index, keyed across dequeue: a worker removes a job from
+	// byIndex/queue the moment it starts downloading it, but Requeue
+	// still needs to know what priority that in-flight piece had so a
+	// transient I/O failure doesn't silently demote it to background
+	// priority out from under a Reader waiting on it.
+	lastPriority map[int]PiecePriority
+}
+
+func NewScheduler() *Scheduler {
+	s := &Scheduler{
+		byIndex:      make(map[int]*pieceJob),
+		lastPriority: make(map[int]PiecePriority),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Add enqueues a piece at PriorityNormal if it isn't already queued.
+func (s *Scheduler) Add(pw *PieceWork) {
+	s.addLocked(pw, PriorityNormal)
+}
+
+// Requeue puts a failed piece's work back on the queue, at the priority
+// it was last assigned rather than unconditionally at PriorityNormal, so
+// a piece a Reader raised to PriorityNow/PriorityNext keeps that
+// priority if the worker fetching it hits a transient error.
+func (s *Scheduler) Requeue(pw *PieceWork) {
+	s.mu.Lock()
+	priority, ok := s.lastPriority[pw.Index]
+	s.mu.Unlock()
+	if !ok {
+		priority = PriorityNormal
+	}
+	s.addLocked(pw, priority)
+}
+
+func (s *Scheduler) addLocked(pw *PieceWork, priority PiecePriority) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastPriority[pw.Index] = priority
+	if _, ok := s.byIndex[pw.Index]; ok {
+		return
+	}
+	job := &pieceJob{work: pw, priority: priority}
+	s.byIndex[pw.Index] = job
+	heap.Push(&s.queue, job)
+	s.cond.Signal()
+}
+
+// SetPriority raises or lowers the priority of a queued piece, and
+// records it in lastPriority regardless of whether the piece is
+// currently queued, so a Requeue after the piece has already been
+// dequeued by a worker still picks it up.
+func (s *Scheduler) SetPriority(index int, priority PiecePriority) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastPriority[index] = priority
+
+	job, ok := s.byIndex[index]
+	if !ok || job.priority == priority {
+		return
+	}
+	job.priority = priority
+	heap.Fix(&s.queue, job.heapIndex)
+	s.cond.Broadcast()
+}
+
+// Next blocks until a piece is available and returns the highest
+// priority one, or ok=false once the scheduler has been closed and
+// drained.
+func (s *Scheduler) Next() (pw *PieceWork, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.queue.Len() == 0 {
+		if s.closed {
+			return nil, false
+		}
+		s.cond.Wait()
+	}
+
+	job := heap.Pop(&s.queue).(*pieceJob)
+	delete(s.byIndex, job.work.Index)
+	return job.work, true
+}
+
+// Close wakes any worker blocked in Next once all queued work is
+// drained, so it can exit instead of waiting forever.
+func (s *Scheduler) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.cond.Broadcast()
+}
+
+// pieceHeap is a container/heap.Interface over pieceJobs, highest
+// priority first.
+type pieceHeap []*pieceJob
+
+func (h pieceHeap) Len() int { return len(h) }
+
+func (h pieceHeap) Less(i, j int) bool { return h[i].priority > h[j].priority }
+
+func (h pieceHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *pieceHeap) Push(x any) {
+	job := x.(*pieceJob)
+	job.heapIndex = len(*h)
+	*h = append(*h, job)
+}
+
+func (h *pieceHeap) Pop() any {
+	old := *h
+	n := len(old)
+	job := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return job
+}
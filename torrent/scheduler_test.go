@@ -0,0 +1,103 @@
+package torrent
+
+import "testing"
+
+func TestSchedulerReturnsHighestPriorityFirst(t *testing.T) {
+	s := NewScheduler()
+	s.Add(&PieceWork{Index: 0})
+	s.addLocked(&PieceWork{Index: 1}, PriorityReadahead)
+	s.addLocked(&PieceWork{Index: 2}, PriorityNow)
+	s.addLocked(&PieceWork{Index: 3}, PriorityNext)
+
+	wantOrder := []int{2, 3, 1, 0} // Now, Next, Readahead, Normal
+	for _, want := range wantOrder {
+		pw, ok := s.Next()
+		if !ok {
+			t.Fatalf("Next() ok = false, want true")
+		}
+		if pw.Index != want {
+			t.Errorf("Next() = piece %d, want %d", pw.Index, want)
+		}
+	}
+}
+
+func TestSchedulerSetPriorityReordersQueuedPiece(t *testing.T) {
+	s := NewScheduler()
+	s.Add(&PieceWork{Index: 0})
+	s.Add(&PieceWork{Index: 1})
+
+	// Piece 1 was added second, so it would normally come out after piece
+	// 0 at equal priority (heap tie order isn't guaranteed, but raising it
+	// to PriorityNow must put it ahead of piece 0's PriorityNormal).
+	s.SetPriority(1, PriorityNow)
+
+	pw, ok := s.Next()
+	if !ok || pw.Index != 1 {
+		t.Fatalf("Next() = %+v, ok=%v, want piece 1", pw, ok)
+	}
+}
+
+func TestSchedulerAddIgnoresDuplicateIndex(t *testing.T) {
+	s := NewScheduler()
+	s.Add(&PieceWork{Index: 0, Length: 1})
+	s.Add(&PieceWork{Index: 0, Length: 2})
+
+	pw, ok := s.Next()
+	if !ok {
+		t.Fatalf("Next() ok = false, want true")
+	}
+	if pw.Length != 1 {
+		t.Errorf("Length = %d, want 1 (first Add should win)", pw.Length)
+	}
+
+	s.Close()
+	if _, ok := s.Next(); ok {
+		t.Error("Next() returned a second piece, want only one queued")
+	}
+}
+
+func TestSchedulerRequeueKeepsLastAssignedPriority(t *testing.T) {
+	s := NewScheduler()
+	s.Add(&PieceWork{Index: 0}) // PriorityNormal
+	s.SetPriority(0, PriorityNow)
+
+	// A worker dequeues it to start the download...
+	pw, ok := s.Next()
+	if !ok || pw.Index != 0 {
+		t.Fatalf("Next() = %+v, ok=%v, want piece 0", pw, ok)
+	}
+
+	// ...a transient error sends it back. It must still be PriorityNow,
+	// not demoted to PriorityNormal, so it stays ahead of background work.
+	s.Add(&PieceWork{Index: 1}) // PriorityNormal, queued before the requeue below
+	s.Requeue(pw)
+
+	pw, ok = s.Next()
+	if !ok || pw.Index != 0 {
+		t.Fatalf("Next() after Requeue = %+v, ok=%v, want piece 0 (PriorityNow) ahead of piece 1 (PriorityNormal)", pw, ok)
+	}
+}
+
+func TestSchedulerRequeueDefaultsToNormalForUnknownPiece(t *testing.T) {
+	s := NewScheduler()
+	s.Requeue(&PieceWork{Index: 5})
+
+	pw, ok := s.Next()
+	if !ok || pw.Index != 5 {
+		t.Fatalf("Next() = %+v, ok=%v, want piece 5", pw, ok)
+	}
+}
+
+func TestSchedulerNextUnblocksOnClose(t *testing.T) {
+	s := NewScheduler()
+	done := make(chan struct{})
+	go func() {
+		if _, ok := s.Next(); ok {
+			t.Error("Next() ok = true after Close on empty queue, want false")
+		}
+		close(done)
+	}()
+
+	s.Close()
+	<-done
+}
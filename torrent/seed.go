@@ -0,0 +1,332 @@
+package torrent
+
+import (
+	"bittorrent/p2p"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxUnchoked is how many interested peers the choking algorithm keeps
+// unchoked at once.
+const maxUnchoked = 4
+
+// maxRequestLength is the largest block a peer is allowed to request in
+// one MsgRequest, matching the blockSize attemptDownloadPiece itself
+// requests with. Anything larger is rejected before we allocate for it.
+const maxRequestLength = 16384
+
+// unchokeInterval is how often the choking algorithm re-ranks peers;
+// every third round also performs an optimistic unchoke, giving roughly
+// one every 30 seconds.
+const unchokeInterval = 10 * time.Second
+
+// seedPeer tracks the server-side state of one inbound connection for as
+// long as Seed is serving it.
+type seedPeer struct {
+	conn net.Conn
+
+	mu         sync.Mutex
+	interested bool
+	choked     bool
+
+	// uploadedSince is bytes sent to this peer since the last choking
+	// round, used to rank peers by upload rate. It's reset every round.
+	uploadedSince int64
+}
+
+// seedRegistry is the set of peers currently connected to a Seed call,
+// shared between serveConn (which adds/removes peers) and the choking
+// loop (which ranks and (un)chokes them).
+type seedRegistry struct {
+	mu    sync.Mutex
+	peers map[*seedPeer]struct{}
+}
+
+func newSeedRegistry() *seedRegistry {
+	return &seedRegistry{peers: make(map[*seedPeer]struct{})}
+}
+
+func (r *seedRegistry) add(p *seedPeer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.peers[p] = struct{}{}
+}
+
+func (r *seedRegistry) remove(p *seedPeer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.peers, p)
+}
+
+func (r *seedRegistry) snapshot() []*seedPeer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	peers := make([]*seedPeer, 0, len(r.peers))
+	for p := range r.peers {
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// Seed opens a TCP listener on listenPort and serves this torrent's
+// pieces to any peer whose handshake infohash matches infoHash, until
+// ctx is cancelled. It validates every inbound handshake against
+// infoHash (a registry of one, until this client seeds more than one
+// torrent per listener), echoes back our own peerID, sends a bitfield
+// derived from the resume state, and then honors MsgInterested,
+// MsgNotInterested, MsgRequest, MsgHave and MsgCancel from each peer. A
+// simple choking algorithm unchokes the top maxUnchoked interested peers
+// by rolling upload rate every unchokeInterval, plus one optimistic
+// unchoke roughly every third round. While Seed is running, Download
+// broadcasts MsgHave to every connected peer as each new piece finishes
+// verifying, so a peer that connected mid-download learns about later
+// pieces without reconnecting.
+func (t *Torrent) Seed(ctx context.Context, infoHash [20]byte, peerID [20]byte, listenPort int) error {
+	if err := t.ensureStorage(); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", listenPort))
+	if err != nil {
+		return fmt.Errorf("seed: could not listen on port %d: %w", listenPort, err)
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	reg := newSeedRegistry()
+	t.seedReg.Store(reg)
+	defer t.seedReg.Store(nil)
+	go t.chokingLoop(ctx, reg)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+				return fmt.Errorf("seed: accept failed: %w", err)
+			}
+		}
+
+		go t.serveConn(conn, infoHash, peerID, reg)
+	}
+}
+
+// serveConn performs the server side of the handshake and, if it
+// matches infoHash, runs the message loop for the rest of the
+// connection's life.
+func (t *Torrent) serveConn(conn net.Conn, infoHash, peerID [20]byte, reg *seedRegistry) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	hs, err := p2p.Unserialize(conn)
+	if err != nil {
+		return
+	}
+	if hs.InfoHash != infoHash {
+		return
+	}
+
+	reply := p2p.Handshake{Pstr: "BitTorrent protocol", InfoHash: infoHash, PeerID: peerID}
+	if _, err := conn.Write(reply.Serialize()); err != nil {
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	peer := &seedPeer{conn: conn, choked: true}
+	reg.add(peer)
+	defer reg.remove(peer)
+
+	t.completedCond.L.Lock()
+	bitfieldCopy := append(Bitfield(nil), t.completed...)
+	t.completedCond.L.Unlock()
+
+	bitfieldMsg := p2p.Message{ID: p2p.MsgBitfield, Payload: bitfieldCopy}
+	if _, err := conn.Write(bitfieldMsg.Serialize()); err != nil {
+		return
+	}
+
+	for {
+		msg, err := p2p.Read(conn)
+		if err != nil {
+			return
+		}
+		if msg == nil {
+			continue // keep-alive
+		}
+
+		switch msg.ID {
+		case p2p.MsgInterested:
+			peer.mu.Lock()
+			peer.interested = true
+			peer.mu.Unlock()
+		case p2p.MsgNotInterested:
+			peer.mu.Lock()
+			peer.interested = false
+			peer.mu.Unlock()
+		case p2p.MsgRequest:
+			if err := t.serveRequest(peer, msg.Payload); err != nil {
+				return
+			}
+		case p2p.MsgHave, p2p.MsgCancel:
+			// Nothing to do: we don't track peer-side piece ownership or
+			// in-flight requests on the serving end.
+		}
+	}
+}
+
+// serveRequest reads the requested block from storage and replies with a
+// MsgPiece, unless we currently have peer choked.
+func (t *Torrent) serveRequest(peer *seedPeer, payload []byte) error {
+	peer.mu.Lock()
+	choked := peer.choked
+	peer.mu.Unlock()
+	if choked {
+		return nil
+	}
+
+	if len(payload) < 12 {
+		return fmt.Errorf("seed: malformed request payload")
+	}
+	index := int(binary.BigEndian.Uint32(payload[0:4]))
+	begin := int(binary.BigEndian.Uint32(payload[4:8]))
+	length := int(binary.BigEndian.Uint32(payload[8:12]))
+	if length <= 0 || length > maxRequestLength {
+		return fmt.Errorf("seed: requested length %d exceeds max block size %d", length, maxRequestLength)
+	}
+	t.completedCond.L.Lock()
+	hasPiece := t.completed.HasPiece(index)
+	t.completedCond.L.Unlock()
+	if !hasPiece {
+		return fmt.Errorf("seed: peer requested piece %d we haven't verified", index)
+	}
+
+	block := make([]byte, length)
+	if _, err := t.Storage.PieceReadAt(index, int64(begin), block); err != nil {
+		return fmt.Errorf("seed: could not read piece %d: %w", index, err)
+	}
+
+	piecePayload := make([]byte, 8+length)
+	binary.BigEndian.PutUint32(piecePayload[0:4], uint32(index))
+	binary.BigEndian.PutUint32(piecePayload[4:8], uint32(begin))
+	copy(piecePayload[8:], block)
+
+	msg := p2p.Message{ID: p2p.MsgPiece, Payload: piecePayload}
+	n, err := peer.conn.Write(msg.Serialize())
+	if err != nil {
+		return err
+	}
+
+	atomic.AddInt64(&peer.uploadedSince, int64(n))
+	atomic.AddInt64(&t.uploaded, int64(n))
+	return nil
+}
+
+// notifyHave broadcasts MsgHave for index to every peer currently
+// connected via Seed, if Seed is running. It's a no-op otherwise, so
+// Download can call it unconditionally every time a piece completes.
+func (t *Torrent) notifyHave(index int) {
+	reg := t.seedReg.Load()
+	if reg == nil {
+		return
+	}
+
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, uint32(index))
+	msg := p2p.Message{ID: p2p.MsgHave, Payload: payload}
+	serialized := msg.Serialize()
+
+	for _, p := range reg.snapshot() {
+		p.conn.Write(serialized)
+	}
+}
+
+// chokingLoop runs the choking algorithm on a ticker until ctx is
+// cancelled.
+func (t *Torrent) chokingLoop(ctx context.Context, reg *seedRegistry) {
+	ticker := time.NewTicker(unchokeInterval)
+	defer ticker.Stop()
+
+	var round int
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			round++
+			runChokingRound(reg, round%3 == 0)
+		}
+	}
+}
+
+// runChokingRound ranks interested peers by bytes uploaded since the
+// last round and unchokes the top maxUnchoked, minus one slot reserved
+// for an optimistic unchoke on optimistic rounds.
+func runChokingRound(reg *seedRegistry, optimistic bool) {
+	peers := reg.snapshot()
+
+	interested := make([]*seedPeer, 0, len(peers))
+	for _, p := range peers {
+		p.mu.Lock()
+		isInterested := p.interested
+		p.mu.Unlock()
+		if isInterested {
+			interested = append(interested, p)
+		}
+	}
+
+	sort.Slice(interested, func(i, j int) bool {
+		return atomic.LoadInt64(&interested[i].uploadedSince) > atomic.LoadInt64(&interested[j].uploadedSince)
+	})
+
+	regularSlots := maxUnchoked
+	if optimistic && len(interested) > 0 {
+		regularSlots--
+	}
+
+	unchoke := make(map[*seedPeer]bool, maxUnchoked)
+	for i := 0; i < regularSlots && i < len(interested); i++ {
+		unchoke[interested[i]] = true
+	}
+
+	if optimistic {
+		candidates := make([]*seedPeer, 0, len(interested))
+		for _, p := range interested {
+			if !unchoke[p] {
+				candidates = append(candidates, p)
+			}
+		}
+		if len(candidates) > 0 {
+			unchoke[candidates[int(randomUint32())%len(candidates)]] = true
+		}
+	}
+
+	for _, p := range peers {
+		p.mu.Lock()
+		wasChoked := p.choked
+		p.choked = !unchoke[p]
+		nowChoked := p.choked
+		p.mu.Unlock()
+
+		atomic.StoreInt64(&p.uploadedSince, 0)
+
+		if wasChoked == nowChoked {
+			continue
+		}
+		id := p2p.MsgUnchoke
+		if nowChoked {
+			id = p2p.MsgChoke
+		}
+		msg := p2p.Message{ID: id}
+		p.conn.Write(msg.Serialize())
+	}
+}
@@ -0,0 +1,143 @@
+package torrent
+
+import (
+	"bittorrent/p2p"
+	"bytes"
+	"net"
+	"testing"
+)
+
+// nopConn satisfies net.Conn so runChokingRound can write a Choke/Unchoke
+// message to a test peer without a real connection; every method but
+// Write panics if called, which none of the code under test does.
+type nopConn struct{ net.Conn }
+
+func (nopConn) Write(b []byte) (int, error) { return len(b), nil }
+
+func newInterestedPeer(uploadedSince int64) *seedPeer {
+	return &seedPeer{conn: nopConn{}, choked: true, interested: true, uploadedSince: uploadedSince}
+}
+
+// recordingConn captures everything written to it so tests can assert on
+// the messages Seed sends, without a real network connection.
+type recordingConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) { return c.buf.Write(b) }
+
+func TestNotifyHaveIsNoopBeforeSeedRuns(t *testing.T) {
+	tr := &Torrent{}
+	// Must not panic even though Seed has never been called.
+	tr.notifyHave(3)
+}
+
+func TestNotifyHaveBroadcastsToConnectedPeers(t *testing.T) {
+	tr := &Torrent{}
+	reg := newSeedRegistry()
+	conn := &recordingConn{}
+	peer := &seedPeer{conn: conn}
+	reg.add(peer)
+	tr.seedReg.Store(reg)
+
+	tr.notifyHave(42)
+
+	msg, err := p2p.Read(&conn.buf)
+	if err != nil {
+		t.Fatalf("p2p.Read of broadcast message failed: %v", err)
+	}
+	if msg.ID != p2p.MsgHave {
+		t.Fatalf("message ID = %d, want MsgHave (%d)", msg.ID, p2p.MsgHave)
+	}
+	index := int(msg.Payload[0])<<24 | int(msg.Payload[1])<<16 | int(msg.Payload[2])<<8 | int(msg.Payload[3])
+	if index != 42 {
+		t.Errorf("MsgHave index = %d, want 42", index)
+	}
+}
+
+func TestRunChokingRoundUnchokesTopUploaders(t *testing.T) {
+	reg := newSeedRegistry()
+	peers := []*seedPeer{
+		newInterestedPeer(10),
+		newInterestedPeer(50),
+		newInterestedPeer(30),
+		newInterestedPeer(20),
+		newInterestedPeer(5), // 5th interested peer, should stay choked
+	}
+	for _, p := range peers {
+		reg.add(p)
+	}
+
+	runChokingRound(reg, false)
+
+	wantUnchoked := map[*seedPeer]bool{peers[1]: true, peers[2]: true, peers[3]: true, peers[0]: true}
+	for _, p := range peers {
+		p.mu.Lock()
+		choked := p.choked
+		p.mu.Unlock()
+		if wantUnchoked[p] == choked {
+			t.Errorf("peer with uploadedSince=%d: choked=%v, want choked=%v", p.uploadedSince, choked, !wantUnchoked[p])
+		}
+	}
+}
+
+func TestRunChokingRoundIgnoresUninterestedPeers(t *testing.T) {
+	reg := newSeedRegistry()
+	uninterested := &seedPeer{conn: nopConn{}, choked: true, interested: false, uploadedSince: 1000}
+	reg.add(uninterested)
+
+	runChokingRound(reg, false)
+
+	uninterested.mu.Lock()
+	defer uninterested.mu.Unlock()
+	if !uninterested.choked {
+		t.Error("uninterested peer was unchoked, want still choked")
+	}
+}
+
+func TestRunChokingRoundOptimisticReservesASlot(t *testing.T) {
+	reg := newSeedRegistry()
+	// Five interested peers all ranked above the lowest; the optimistic
+	// round must leave exactly maxUnchoked peers unchoked in total, with
+	// one of the regular top (maxUnchoked-1) slots replaced by a peer
+	// chosen from the rest.
+	peers := make([]*seedPeer, 5)
+	for i := range peers {
+		peers[i] = newInterestedPeer(int64(100 - i*10))
+		reg.add(peers[i])
+	}
+
+	runChokingRound(reg, true)
+
+	unchokedCount := 0
+	for _, p := range peers {
+		p.mu.Lock()
+		if !p.choked {
+			unchokedCount++
+		}
+		p.mu.Unlock()
+	}
+	if unchokedCount != maxUnchoked {
+		t.Errorf("unchoked count = %d, want %d", unchokedCount, maxUnchoked)
+	}
+
+	// The very top-ranked peer always keeps a regular slot.
+	peers[0].mu.Lock()
+	defer peers[0].mu.Unlock()
+	if peers[0].choked {
+		t.Error("top-ranked peer was choked during an optimistic round, want unchoked")
+	}
+}
+
+func TestRunChokingRoundResetsUploadedSince(t *testing.T) {
+	reg := newSeedRegistry()
+	p := newInterestedPeer(1234)
+	reg.add(p)
+
+	runChokingRound(reg, false)
+
+	if p.uploadedSince != 0 {
+		t.Errorf("uploadedSince = %d after round, want 0", p.uploadedSince)
+	}
+}
@@ -2,16 +2,15 @@ package torrent
 
 import (
 	"bittorrent/bencode"
+	"bittorrent/storage"
 	"bytes"
 	"crypto/sha1"
-	"encoding/binary"
 	"fmt"
-	"io"
 	"net"
-	"net/http"
-	"net/url"
 	"os"
-	"strconv"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 )
 
 type Peer struct {
@@ -33,8 +32,42 @@ type InfoDict struct {
 }
 
 type Torrent struct {
-	Announce string   `bencode:"announce"`
-	Info     InfoDict `bencode:"info"`
+	Announce     string     `bencode:"announce"`
+	AnnounceList [][]string `bencode:"announce-list,omitempty"`
+	Info         InfoDict   `bencode:"info"`
+
+	// Storage is where Download reads and writes piece data. If nil,
+	// Download allocates a FileStorage rooted at the current directory.
+	Storage storage.Backend
+
+	// trackerCache keeps one Tracker per announce URL alive for the life of
+	// the Torrent, so UDP connection_id caching (BEP 15) survives across
+	// re-announces.
+	trackerCache   map[string]Tracker
+	trackerCacheMu sync.Mutex
+
+	// Streaming state, populated by Download and consumed by NewReader.
+	scheduler     *Scheduler
+	completed     Bitfield
+	completedCond *sync.Cond
+
+	// storageMu guards ensureStorage's lazy init of Storage, completed and
+	// completedCond, so Download and Seed can be run concurrently on the
+	// same Torrent without racing to set them up.
+	storageMu sync.Mutex
+
+	// seedReg is the set of peers currently connected via Seed, if any,
+	// so Download can broadcast MsgHave to them as pieces finish
+	// verifying without a peer having to reconnect to see new progress.
+	// nil until Seed is called.
+	seedReg atomic.Pointer[seedRegistry]
+
+	// downloaded and uploaded are running byte totals, updated by Download
+	// and Seed and read by RequestPeers when announcing. Accessed with the
+	// atomic package since Seed's per-connection goroutines update
+	// uploaded concurrently with announces.
+	downloaded int64
+	uploaded   int64
 }
 
 type Piece struct {
@@ -51,18 +84,18 @@ func (bf Bitfield) HasPiece(index int) bool {
 	if byteIndex < 0 || byteIndex >= len(bf) {
 		return false
 	}
-	return (bf[byteIndex] >> (7 - offset) % 1) != 0
+	return (bf[byteIndex]>>(7-offset))&1 != 0
 }
 
 func (bf Bitfield) SetPiece(index int) {
 	byteIndex := index / 8
 	offset := index % 8
-	if byteIndex >= 0 || byteIndex < len(bf) {
+	if byteIndex >= 0 && byteIndex < len(bf) {
 		bf[byteIndex] |= (1 << (7 - offset))
 	}
 }
 
-func (t *Torrent) CreatePieceList(infoHash [20]byte) []Piece {
+func (t *Torrent) CreatePieceList() []Piece {
 	rawHashes := []byte(t.Info.Pieces)
 	numPieces := len(rawHashes) / 20
 	pieces := make([]Piece, numPieces)
@@ -103,173 +136,158 @@ func Open(path string) (*Torrent, [20]byte, error) {
 	}
 
 	decoder := bencode.NewDecoder(data)
-	result, err := decoder.Decode()
-	if err != nil {
-		return nil, [20]byte{}, err
-	}
-
-	fullDict, ok := result.(map[string]any)
-	if !ok {
-		return nil, [20]byte{}, fmt.Errorf("invalid torrent format")
-	}
-
 	t := &Torrent{}
-
-	if announce, ok := fullDict["announce"].([]byte); ok {
-		t.Announce = string(announce)
+	if err := decoder.Unmarshal(t); err != nil {
+		return nil, [20]byte{}, fmt.Errorf("invalid torrent format: %w", err)
 	}
 
-	infoMap, ok := fullDict["info"].(map[string]any)
-	if !ok {
+	if len(decoder.RawInfo) == 0 {
 		return nil, [20]byte{}, fmt.Errorf("missing info dict")
 	}
 
-	t.Info.Name = string(infoMap["name"].([]byte))
-	t.Info.PieceLength = infoMap["piece length"].(int64)
-	t.Info.Pieces = string(infoMap["pieces"].([]byte))
-
-	if length, ok := infoMap["length"].(int64); ok {
-		t.Info.Length = length
-	} else if files, ok := infoMap["files"].([]any); ok {
-		for _, f := range files {
-			fDict := f.(map[string]any)
-			t.Info.Files = append(t.Info.Files, File{
-				Length: fDict["length"].(int64),
-			})
-		}
-	}
-
 	infoHash := sha1.Sum(decoder.RawInfo)
 
 	return t, infoHash, nil
 }
 
-func (t *Torrent) BuildTrackerURL(infoHash [20]byte, peerID string, port int) (string, error) {
-	u, err := url.Parse(t.Announce)
-	if err != nil {
-		return "", err
-	}
-
-	var left int64
+// totalLength returns the total size in bytes of this torrent's content.
+func (t *Torrent) totalLength() int64 {
 	if t.Info.Length > 0 {
-		left = t.Info.Length
-	} else {
-		for _, f := range t.Info.Files {
-			left += f.Length
-		}
+		return t.Info.Length
 	}
-
-	escapedHash := ""
-	for _, b := range infoHash {
-		escapedHash += fmt.Sprintf("%%%02x", b)
+	var length int64
+	for _, f := range t.Info.Files {
+		length += f.Length
 	}
-
-	params := url.Values{}
-	params.Add("peer_id", peerID)
-	params.Add("port", strconv.Itoa(port))
-	params.Add("uploaded", "0")
-	params.Add("downloaded", "0")
-	params.Add("left", strconv.FormatInt(left, 10))
-	params.Add("compact", "1")
-
-	return fmt.Sprintf("%s?info_hash=%s&%s", u.String(), escapedHash, params.Encode()), nil
+	return length
 }
 
-func RequestPeers(trackerURL string) ([]Peer, error) {
-	resp, err := http.Get(trackerURL)
-	if err != nil {
-		return nil, err
+// fileList returns this torrent's layout as storage.FileInfo entries,
+// in the order FileStorage should concatenate them.
+func (t *Torrent) fileList() []storage.FileInfo {
+	if len(t.Info.Files) == 0 {
+		return []storage.FileInfo{{Path: t.Info.Name, Length: t.Info.Length}}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("tracker returned status %d", resp.StatusCode)
-	}
-
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("could not read tracker response: %w", err)
-	}
-
-	decorder := bencode.NewDecoder(data)
-	result, err := decorder.Decode()
-	if err != nil {
-		return nil, fmt.Errorf("could not bdecode tracker response: %w", err)
+	files := make([]storage.FileInfo, len(t.Info.Files))
+	for i, f := range t.Info.Files {
+		files[i] = storage.FileInfo{
+			Path:   filepath.Join(append([]string{t.Info.Name}, f.Path...)...),
+			Length: f.Length,
+		}
 	}
+	return files
+}
 
-	resDict := result.(map[string]any)
-	piecesBolb, ok := resDict["peers"].([]byte)
-	if !ok {
-		if msg, ok := resDict["failure reason"].([]byte); ok {
-			return nil, fmt.Errorf("tracker failed: %s", string(msg))
+// ensureStorage lazily allocates a FileStorage backend and verifies
+// whatever is already on disk, so Download and Seed can both run without
+// the caller having to set up Storage and completed themselves first.
+// It's safe to call concurrently from both, since Download and Seed may
+// run against the same Torrent at once.
+func (t *Torrent) ensureStorage() error {
+	t.storageMu.Lock()
+	defer t.storageMu.Unlock()
+
+	if t.Storage == nil {
+		backend, err := storage.NewFileStorage(".", t.Info.PieceLength, t.fileList())
+		if err != nil {
+			return fmt.Errorf("could not initialize storage: %v", err)
 		}
-		return nil, fmt.Errorf("tracker response missing peers")
+		t.Storage = backend
 	}
 
-	return parsePeers(piecesBolb)
-}
+	if t.completed == nil {
+		numPieces := len(t.CreatePieceList())
+		wantBitfieldLen := (numPieces + 7) / 8
 
-func parsePeers(peerBinary []byte) ([]Peer, error) {
-	const peerSize = 6 // 4 bytes for IP, 2 bytes for Port
-	if len(peerBinary)%peerSize != 0 {
-		return nil, fmt.Errorf("recieved malformed compact peer list")
+		resumed, err := t.loadResume()
+		if err != nil {
+			return fmt.Errorf("could not read resume sidecar: %v", err)
+		}
+		if resumed != nil && len(resumed.Bitfield) == wantBitfieldLen {
+			t.completed = Bitfield(resumed.Bitfield)
+			atomic.StoreInt64(&t.downloaded, resumed.Downloaded)
+			atomic.StoreInt64(&t.uploaded, resumed.Uploaded)
+		} else {
+			completed, err := t.VerifyLocalData(t.Storage)
+			if err != nil {
+				return fmt.Errorf("could not verify local data: %v", err)
+			}
+			t.completed = completed
+		}
 	}
 
-	numPeers := len(peerBinary) / peerSize
-	peers := make([]Peer, numPeers)
-
-	for i := 0; i < numPeers; i++ {
-		offset := i * peerSize
-		peers[i].IP = net.IP(peerBinary[offset : offset+4])
-		peers[i].Port = binary.BigEndian.Uint16(peerBinary[offset+4 : offset+6])
+	if t.completedCond == nil {
+		t.completedCond = sync.NewCond(&sync.Mutex{})
 	}
 
-	return peers, nil
+	return nil
 }
 
 // the manager
 func (t *Torrent) Download(peers []Peer, infoHash [20]byte, peerId [20]byte) error {
-	pieces := t.CreatePieceList(infoHash)
-	workQueue := make(chan *PieceWork, len(pieces))
+	pieces := t.CreatePieceList()
+
+	if err := t.ensureStorage(); err != nil {
+		return err
+	}
+	defer t.Storage.Close()
+
+	t.scheduler = NewScheduler()
 	results := make(chan *PieceResult)
 
 	stats := make([]WorkerStatus, len(peers))
 
+	doneCount := 0
 	for _, p := range pieces {
-		workQueue <- &PieceWork{p.Index, p.Hash, p.Length}
+		if t.completed.HasPiece(p.Index) {
+			doneCount++
+			atomic.AddInt64(&t.downloaded, int64(p.Length))
+			continue
+		}
+		t.scheduler.Add(&PieceWork{p.Index, p.Hash, p.Length})
 	}
-
-	for i, p := range peers {
-		go t.startWorker(p, infoHash, peerId, workQueue, results, &stats[i])
+	if doneCount > 0 {
+		fmt.Printf("[resume] %d/%d pieces already complete on disk\n", doneCount, len(pieces))
 	}
 
-	file, err := os.OpenFile(t.Info.Name, os.O_CREATE|os.O_WRONLY, 0666)
-	if err != nil {
-		return fmt.Errorf("could not create file: %v", err)
+	for i, p := range peers {
+		go t.startWorker(p, infoHash, peerId, t.scheduler, results, &stats[i])
 	}
-	defer file.Close()
 
-	doneCount := 0
 	go t.DisplayStats(stats, &doneCount, len(pieces))
 	for doneCount < len(pieces) {
 		res := <-results
 		hash := sha1.Sum(res.Buf)
 		if !bytes.Equal(hash[:], pieces[res.Index].Hash[:]) {
 			fmt.Printf("[!] Index %d failed hash check. Re-queuing...\n", res.Index)
-			workQueue <- &PieceWork{res.Index, pieces[res.Index].Hash, pieces[res.Index].Length}
+			t.scheduler.Add(&PieceWork{res.Index, pieces[res.Index].Hash, pieces[res.Index].Length})
 			continue
 		}
 
-		offset := int64(res.Index) * t.Info.PieceLength
-		_, err := file.WriteAt(res.Buf, offset)
-		if err != nil {
+		if _, err := t.Storage.PieceWriteAt(res.Index, 0, res.Buf); err != nil {
 			return fmt.Errorf("failed writing to disk: %v", err)
 		}
+		if err := t.Storage.MarkComplete(res.Index); err != nil {
+			return fmt.Errorf("failed marking piece %d complete: %v", res.Index, err)
+		}
+
+		t.completedCond.L.Lock()
+		t.completed.SetPiece(res.Index)
+		t.completedCond.Broadcast()
+		t.completedCond.L.Unlock()
+		t.notifyHave(res.Index)
+
 		doneCount++
+		downloaded := atomic.AddInt64(&t.downloaded, int64(len(res.Buf)))
+		if err := t.persistResume(downloaded, atomic.LoadInt64(&t.uploaded)); err != nil {
+			fmt.Printf("[!] could not persist resume data: %v\n", err)
+		}
+
 		percent := float64(doneCount) / float64(len(pieces)) * 100
 		fmt.Printf("[%0.2f%%] Downloaded piece %d from worker\n", percent, res.Index)
 	}
 
-	close(workQueue)
+	t.scheduler.Close()
 	return nil
 }
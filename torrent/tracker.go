@@ -0,0 +1,316 @@
+package torrent
+
+import (
+	"bittorrent/bencode"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tracker announces to a single tracker and returns the peers it hands
+// back. HTTPTracker (BEP 3) and UDPTracker (BEP 15) both implement it so
+// RequestPeers can walk a torrent's tiers without caring which scheme
+// each one uses.
+type Tracker interface {
+	Announce(infoHash [20]byte, peerID [20]byte, port int, uploaded, downloaded, left int64) ([]Peer, error)
+}
+
+// NewTracker builds the Tracker implementation appropriate for
+// announceURL's scheme.
+func NewTracker(announceURL string) (Tracker, error) {
+	u, err := url.Parse(announceURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracker URL %q: %w", announceURL, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return &HTTPTracker{URL: announceURL}, nil
+	case "udp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("udp tracker URL %q is missing a host", announceURL)
+		}
+		return &UDPTracker{addr: u.Host}, nil
+	default:
+		return nil, fmt.Errorf("unsupported tracker scheme %q", u.Scheme)
+	}
+}
+
+// trackerTiers returns the trackers to try in BEP 12 order: the
+// announce-list if present, otherwise the single legacy Announce URL.
+func (t *Torrent) trackerTiers() [][]string {
+	if len(t.AnnounceList) > 0 {
+		return t.AnnounceList
+	}
+	if t.Announce != "" {
+		return [][]string{{t.Announce}}
+	}
+	return nil
+}
+
+// trackerFor returns the cached Tracker for announceURL, creating and
+// caching one if this is the first time it's been announced to.
+func (t *Torrent) trackerFor(announceURL string) (Tracker, error) {
+	t.trackerCacheMu.Lock()
+	defer t.trackerCacheMu.Unlock()
+
+	if tr, ok := t.trackerCache[announceURL]; ok {
+		return tr, nil
+	}
+
+	tr, err := NewTracker(announceURL)
+	if err != nil {
+		return nil, err
+	}
+	if t.trackerCache == nil {
+		t.trackerCache = make(map[string]Tracker)
+	}
+	t.trackerCache[announceURL] = tr
+	return tr, nil
+}
+
+// RequestPeers announces to this torrent's trackers in tiered order,
+// returning the peer list from the first one that answers.
+func (t *Torrent) RequestPeers(infoHash [20]byte, peerID [20]byte, port int) ([]Peer, error) {
+	tiers := t.trackerTiers()
+	if len(tiers) == 0 {
+		return nil, fmt.Errorf("torrent has no trackers")
+	}
+
+	left := t.totalLength()
+	uploaded := atomic.LoadInt64(&t.uploaded)
+	downloaded := atomic.LoadInt64(&t.downloaded)
+
+	var lastErr error
+	for _, tier := range tiers {
+		for _, announceURL := range tier {
+			tr, err := t.trackerFor(announceURL)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			peers, err := tr.Announce(infoHash, peerID, port, uploaded, downloaded, left)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			return peers, nil
+		}
+	}
+
+	return nil, fmt.Errorf("all trackers failed, last error: %w", lastErr)
+}
+
+// HTTPTracker announces over plain HTTP(S), per BEP 3.
+type HTTPTracker struct {
+	URL string
+}
+
+func (ht *HTTPTracker) Announce(infoHash [20]byte, peerID [20]byte, port int, uploaded, downloaded, left int64) ([]Peer, error) {
+	u, err := url.Parse(ht.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	escapedHash := ""
+	for _, b := range infoHash {
+		escapedHash += fmt.Sprintf("%%%02x", b)
+	}
+
+	params := url.Values{}
+	params.Add("peer_id", string(peerID[:]))
+	params.Add("port", strconv.Itoa(port))
+	params.Add("uploaded", strconv.FormatInt(uploaded, 10))
+	params.Add("downloaded", strconv.FormatInt(downloaded, 10))
+	params.Add("left", strconv.FormatInt(left, 10))
+	params.Add("compact", "1")
+
+	trackerURL := fmt.Sprintf("%s?info_hash=%s&%s", u.String(), escapedHash, params.Encode())
+
+	resp, err := http.Get(trackerURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tracker returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read tracker response: %w", err)
+	}
+
+	decoder := bencode.NewDecoder(data)
+	result, err := decoder.Decode()
+	if err != nil {
+		return nil, fmt.Errorf("could not bdecode tracker response: %w", err)
+	}
+
+	resDict := result.(map[string]any)
+	piecesBolb, ok := resDict["peers"].([]byte)
+	if !ok {
+		if msg, ok := resDict["failure reason"].([]byte); ok {
+			return nil, fmt.Errorf("tracker failed: %s", string(msg))
+		}
+		return nil, fmt.Errorf("tracker response missing peers")
+	}
+
+	return parsePeers(piecesBolb)
+}
+
+func parsePeers(peerBinary []byte) ([]Peer, error) {
+	const peerSize = 6 // 4 bytes for IP, 2 bytes for Port
+	if len(peerBinary)%peerSize != 0 {
+		return nil, fmt.Errorf("recieved malformed compact peer list")
+	}
+
+	numPeers := len(peerBinary) / peerSize
+	peers := make([]Peer, numPeers)
+
+	for i := 0; i < numPeers; i++ {
+		offset := i * peerSize
+		peers[i].IP = net.IP(peerBinary[offset : offset+4])
+		peers[i].Port = binary.BigEndian.Uint16(peerBinary[offset+4 : offset+6])
+	}
+
+	return peers, nil
+}
+
+// UDP tracker protocol constants (BEP 15).
+const (
+	udpProtocolMagic  uint64 = 0x41727101980
+	udpActionConnect  uint32 = 0
+	udpActionAnnounce uint32 = 1
+
+	udpMaxRetries = 8 // last wait is 15·2^8s ≈ 64 minutes; ≈ 128 minutes in all before giving up
+)
+
+// UDPTracker announces over the lightweight UDP protocol, per BEP 15.
+type UDPTracker struct {
+	addr string
+
+	mu           sync.Mutex
+	connID       uint64
+	connIDExpiry time.Time
+}
+
+func (ut *UDPTracker) Announce(infoHash [20]byte, peerID [20]byte, port int, uploaded, downloaded, left int64) ([]Peer, error) {
+	conn, err := net.DialTimeout("udp", ut.addr, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("udp tracker: dial failed: %w", err)
+	}
+	defer conn.Close()
+
+	connID, err := ut.connectionID(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	txID := randomUint32()
+	req := make([]byte, 98)
+	binary.BigEndian.PutUint64(req[0:8], connID)
+	binary.BigEndian.PutUint32(req[8:12], udpActionAnnounce)
+	binary.BigEndian.PutUint32(req[12:16], txID)
+	copy(req[16:36], infoHash[:])
+	copy(req[36:56], peerID[:])
+	binary.BigEndian.PutUint64(req[56:64], uint64(downloaded))
+	binary.BigEndian.PutUint64(req[64:72], uint64(left))
+	binary.BigEndian.PutUint64(req[72:80], uint64(uploaded))
+	binary.BigEndian.PutUint32(req[80:84], 0)              // event: none
+	binary.BigEndian.PutUint32(req[84:88], 0)              // IP: default
+	binary.BigEndian.PutUint32(req[88:92], randomUint32()) // key
+	binary.BigEndian.PutUint32(req[92:96], 0xFFFFFFFF)     // num_want: -1, let the tracker decide
+	binary.BigEndian.PutUint16(req[96:98], uint16(port))
+
+	resp, err := udpRoundTrip(conn, req, 20)
+	if err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint32(resp[0:4]) != udpActionAnnounce {
+		return nil, fmt.Errorf("udp tracker: unexpected action in announce response")
+	}
+	if binary.BigEndian.Uint32(resp[4:8]) != txID {
+		return nil, fmt.Errorf("udp tracker: transaction id mismatch")
+	}
+
+	return parsePeers(resp[20:])
+}
+
+// connectionID returns a connection_id valid for the next minute,
+// reusing the cached one if it hasn't expired yet (BEP 15 §"connect").
+func (ut *UDPTracker) connectionID(conn net.Conn) (uint64, error) {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	if time.Now().Before(ut.connIDExpiry) {
+		return ut.connID, nil
+	}
+
+	txID := randomUint32()
+	req := make([]byte, 16)
+	binary.BigEndian.PutUint64(req[0:8], udpProtocolMagic)
+	binary.BigEndian.PutUint32(req[8:12], udpActionConnect)
+	binary.BigEndian.PutUint32(req[12:16], txID)
+
+	resp, err := udpRoundTrip(conn, req, 16)
+	if err != nil {
+		return 0, err
+	}
+	if binary.BigEndian.Uint32(resp[0:4]) != udpActionConnect {
+		return 0, fmt.Errorf("udp tracker: unexpected action in connect response")
+	}
+	if binary.BigEndian.Uint32(resp[4:8]) != txID {
+		return 0, fmt.Errorf("udp tracker: transaction id mismatch")
+	}
+
+	ut.connID = binary.BigEndian.Uint64(resp[8:16])
+	ut.connIDExpiry = time.Now().Add(60 * time.Second)
+	return ut.connID, nil
+}
+
+// udpRoundTrip sends req and waits for a response of at least minLen
+// bytes, retransmitting with the exponential backoff from BEP 15
+// (15·2^n seconds, n up to 8) until one arrives.
+func udpRoundTrip(conn net.Conn, req []byte, minLen int) ([]byte, error) {
+	buf := make([]byte, 2048)
+
+	for n := 0; n <= udpMaxRetries; n++ {
+		if _, err := conn.Write(req); err != nil {
+			return nil, fmt.Errorf("udp tracker: write failed: %w", err)
+		}
+
+		timeout := time.Duration(15*(1<<uint(n))) * time.Second
+		conn.SetReadDeadline(time.Now().Add(timeout))
+
+		read, err := conn.Read(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			return nil, fmt.Errorf("udp tracker: read failed: %w", err)
+		}
+		if read < minLen {
+			return nil, fmt.Errorf("udp tracker: short response (%d bytes)", read)
+		}
+		return buf[:read], nil
+	}
+
+	return nil, fmt.Errorf("udp tracker: no response after %d retries", udpMaxRetries)
+}
+
+func randomUint32() uint32 {
+	var b [4]byte
+	rand.Read(b[:])
+	return binary.BigEndian.Uint32(b[:])
+}
@@ -0,0 +1,66 @@
+package torrent
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParsePeers(t *testing.T) {
+	// Two compact peers: 1.2.3.4:6881 and 5.6.7.8:51413.
+	raw := []byte{1, 2, 3, 4, 0x1A, 0xE1, 5, 6, 7, 8, 0xC8, 0xD5}
+
+	peers, err := parsePeers(raw)
+	if err != nil {
+		t.Fatalf("parsePeers returned error: %v", err)
+	}
+
+	want := []Peer{
+		{IP: net.IPv4(1, 2, 3, 4), Port: 6881},
+		{IP: net.IPv4(5, 6, 7, 8), Port: 51413},
+	}
+	if len(peers) != len(want) {
+		t.Fatalf("got %d peers, want %d", len(peers), len(want))
+	}
+	for i := range want {
+		if !peers[i].IP.Equal(want[i].IP) || peers[i].Port != want[i].Port {
+			t.Errorf("peer %d = %+v, want %+v", i, peers[i], want[i])
+		}
+	}
+}
+
+func TestParsePeersMalformed(t *testing.T) {
+	if _, err := parsePeers([]byte{1, 2, 3}); err == nil {
+		t.Error("expected error for peer list not a multiple of 6 bytes, got nil")
+	}
+}
+
+func TestTrackerTiersPrefersAnnounceList(t *testing.T) {
+	tr := &Torrent{
+		Announce:     "udp://legacy.example:80",
+		AnnounceList: [][]string{{"udp://a.example:80"}, {"udp://b.example:80", "udp://c.example:80"}},
+	}
+
+	got := tr.trackerTiers()
+	want := [][]string{{"udp://a.example:80"}, {"udp://b.example:80", "udp://c.example:80"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("trackerTiers() = %v, want %v", got, want)
+	}
+}
+
+func TestTrackerTiersFallsBackToAnnounce(t *testing.T) {
+	tr := &Torrent{Announce: "udp://legacy.example:80"}
+
+	got := tr.trackerTiers()
+	want := [][]string{{"udp://legacy.example:80"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("trackerTiers() = %v, want %v", got, want)
+	}
+}
+
+func TestTrackerTiersEmpty(t *testing.T) {
+	tr := &Torrent{}
+	if got := tr.trackerTiers(); got != nil {
+		t.Errorf("trackerTiers() = %v, want nil", got)
+	}
+}
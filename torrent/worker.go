@@ -30,7 +30,7 @@ type WorkerStatus struct {
 	Status  string // "Connecting", "Downloading", "Choked", "Idle"
 }
 
-func (t *Torrent) startWorker(peer Peer, infoHash [20]byte, peerID [20]byte, workQueue chan *PieceWork, results chan *PieceResult, ws *WorkerStatus) {
+func (t *Torrent) startWorker(peer Peer, infoHash [20]byte, peerID [20]byte, scheduler *Scheduler, results chan *PieceResult, ws *WorkerStatus) {
 	address := net.JoinHostPort(peer.IP.String(), strconv.Itoa(int(peer.Port)))
 	ws.Address = address
 	ws.Status = "Connecting"
@@ -66,7 +66,12 @@ func (t *Torrent) startWorker(peer Peer, infoHash [20]byte, peerID [20]byte, wor
 	interested := p2p.Message{ID: p2p.MsgInterested}
 	conn.Write(interested.Serialize())
 
-	for pw := range workQueue {
+	for {
+		pw, ok := scheduler.Next()
+		if !ok {
+			return
+		}
+
 		ws.Piece = pw.Index
 		ws.Status = "Downloading"
 
@@ -74,7 +79,7 @@ func (t *Torrent) startWorker(peer Peer, infoHash [20]byte, peerID [20]byte, wor
 		if err != nil {
 			fmt.Printf("[Worker] %s: Piece %d failed: %v\n", address, pw.Index, err)
 			ws.Status = "Error"
-			workQueue <- pw
+			scheduler.Requeue(pw)
 			return
 		}
 		results <- &PieceResult{Index: pw.Index, Buf: buf}